@@ -0,0 +1,108 @@
+// Command node_exporter exposes machine-level metrics for Prometheus to
+// scrape, via the collectors registered in the collector package.
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/promlog"
+	promlogflag "github.com/prometheus/common/promlog/flag"
+	"github.com/prometheus/common/version"
+
+	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/collector/alarm"
+)
+
+// shutdownTimeout bounds how long Stop waits for startable collectors to
+// drain in-flight work once a shutdown signal is received.
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	var (
+		metricsPath       = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
+		listenAddress     = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9100").String()
+		alarmConfigPath   = kingpin.Flag("collector.alarm.config", "Path to a YAML file of alerting rules to evaluate against the exporter's own metrics. Alerting is disabled if unset.").Default("").String()
+		alarmAlertmanager = kingpin.Flag("collector.alarm.alertmanager-url", "Base URL of the Alertmanager to push alerts to.").Default("http://127.0.0.1:9093").String()
+		alarmEvalInterval = kingpin.Flag("collector.alarm.eval-interval", "How often to evaluate alarm rules.").Default("15s").Duration()
+	)
+
+	promlogConfig := &promlog.Config{}
+	promlogflag.AddFlags(kingpin.CommandLine, promlogConfig)
+	kingpin.Version(version.Print("node_exporter"))
+	kingpin.HelpFlag.Short('h')
+	kingpin.Parse()
+	logger := promlog.New(promlogConfig)
+
+	nc, err := collector.NewNodeCollector(logger)
+	if err != nil {
+		level.Error(logger).Log("msg", "couldn't create collector", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := nc.Start(ctx); err != nil {
+		level.Error(logger).Log("msg", "couldn't start collectors", "err", err)
+		os.Exit(1)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(nc)
+
+	var alarmManager *alarm.Manager
+	if *alarmConfigPath != "" {
+		alarmManager, err = alarm.NewManager(logger, registry, *alarmConfigPath, *alarmAlertmanager, *alarmEvalInterval)
+		if err != nil {
+			level.Error(logger).Log("msg", "couldn't create alarm manager", "err", err)
+			os.Exit(1)
+		}
+		if err := alarmManager.Start(ctx); err != nil {
+			level.Error(logger).Log("msg", "couldn't start alarm manager", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: *listenAddress, Handler: mux}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		level.Info(logger).Log("msg", "listening", "address", *listenAddress)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			level.Error(logger).Log("msg", "http server failed", "err", err)
+		}
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+
+	level.Info(logger).Log("msg", "shutting down")
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	nc.Stop(shutdownCtx)
+	if alarmManager != nil {
+		if err := alarmManager.Stop(shutdownCtx); err != nil {
+			level.Warn(logger).Log("msg", "alarm manager did not stop cleanly", "err", err)
+		}
+	}
+	_ = server.Shutdown(shutdownCtx)
+
+	wg.Wait()
+}
@@ -0,0 +1,361 @@
+// Package alarm evaluates simple threshold rules against the metrics
+// node_exporter itself produces and pushes alerts to Alertmanager, so a
+// single exporter can act as a self-contained edge-monitoring agent in
+// environments where running a full Prometheus server isn't practical.
+// Evaluation runs on its own ticker, independent of Prometheus scrapes, so
+// alerts still fire even if nothing is scraping the exporter.
+package alarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v2"
+)
+
+// exprPattern matches the small rule language this package supports:
+// a metric name, an optional label selector, a comparison operator and a
+// numeric threshold, e.g. `node_filebeat_up{process_name="filebeat"} == 0`.
+var exprPattern = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)\s*(\{[^}]*\})?\s*(==|!=|>=|<=|>|<)\s*([-+]?[0-9]*\.?[0-9]+)\s*$`)
+
+var labelPattern = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)\s*=\s*"([^"]*)"`)
+
+// Rule describes one alerting rule, as loaded from --collector.alarm.config.
+type Rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         time.Duration     `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+
+	metric   string
+	selector map[string]string
+	op       string
+	value    float64
+}
+
+// Config is the top-level document loaded from --collector.alarm.config.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadConfig reads and parses a rules file, compiling each rule's expr.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	for i := range config.Rules {
+		if err := config.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", config.Rules[i].Alert, err)
+		}
+	}
+
+	return &config, nil
+}
+
+func (r *Rule) compile() error {
+	m := exprPattern.FindStringSubmatch(r.Expr)
+	if m == nil {
+		return fmt.Errorf("invalid expr %q", r.Expr)
+	}
+
+	value, err := strconv.ParseFloat(m[4], 64)
+	if err != nil {
+		return fmt.Errorf("invalid threshold %q: %w", m[4], err)
+	}
+
+	r.metric = m[1]
+	r.op = m[3]
+	r.value = value
+
+	if m[2] != "" {
+		r.selector = map[string]string{}
+		for _, lm := range labelPattern.FindAllStringSubmatch(m[2], -1) {
+			r.selector[lm[1]] = lm[2]
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether the given label set satisfies the rule's selector.
+func (r *Rule) matches(labels map[string]string) bool {
+	for k, v := range r.selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// breaches reports whether value breaches the rule's threshold.
+func (r *Rule) breaches(value float64) bool {
+	switch r.op {
+	case "==":
+		return value == r.value
+	case "!=":
+		return value != r.value
+	case ">":
+		return value > r.value
+	case "<":
+		return value < r.value
+	case ">=":
+		return value >= r.value
+	case "<=":
+		return value <= r.value
+	default:
+		return false
+	}
+}
+
+// alertmanagerAlert is the payload shape expected by Alertmanager's v2
+// `POST /api/v2/alerts` endpoint.
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// ruleState tracks one rule's breach history so Manager can honour the
+// rule's `for:` duration before firing, and know when to send a resolved
+// notification once the breach clears.
+type ruleState struct {
+	breachingSince time.Time // zero if not currently breaching
+	firing         bool      // whether an alert for this rule is currently active in Alertmanager
+	startsAt       time.Time // StartsAt recorded when the alert started firing
+}
+
+// Manager evaluates a set of Rules on a ticker and pushes alerts to
+// Alertmanager. It implements the same Start/Stop lifecycle contract as
+// collector.StartableCollector, since it owns a background goroutine.
+type Manager struct {
+	logger          log.Logger
+	gatherer        prometheus.Gatherer
+	rules           []Rule
+	alertmanagerURL string
+	interval        time.Duration
+	client          *http.Client
+
+	mu    sync.Mutex
+	state map[string]*ruleState
+
+	done chan struct{}
+}
+
+// NewManager loads configPath and returns a Manager ready to evaluate its
+// rules against gatherer's current metrics every interval, pushing alerts to
+// alertmanagerURL.
+func NewManager(logger log.Logger, gatherer prometheus.Gatherer, configPath, alertmanagerURL string, interval time.Duration) (*Manager, error) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alarm config %q: %w", configPath, err)
+	}
+
+	state := make(map[string]*ruleState, len(config.Rules))
+	for _, r := range config.Rules {
+		state[r.Alert] = &ruleState{}
+	}
+
+	return &Manager{
+		logger:          logger,
+		gatherer:        gatherer,
+		rules:           config.Rules,
+		alertmanagerURL: alertmanagerURL,
+		interval:        interval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		state:           state,
+	}, nil
+}
+
+// Start begins evaluating rules on a ticker in the background. It returns
+// once the evaluation goroutine has been launched.
+func (m *Manager) Start(ctx context.Context) error {
+	m.done = make(chan struct{})
+	go m.run(ctx)
+	return nil
+}
+
+// Stop waits for the evaluation goroutine to exit, up to ctx's deadline.
+func (m *Manager) Stop(ctx context.Context) error {
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) run(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.evaluate()
+		}
+	}
+}
+
+// evaluate gathers the exporter's own metrics once and checks every rule
+// against them, firing or resolving alerts as their breach state changes.
+func (m *Manager) evaluate() {
+	families, err := m.gatherer.Gather()
+	if err != nil {
+		level.Error(m.logger).Log("msg", "failed to gather metrics for alarm evaluation", "err", err)
+		return
+	}
+
+	samples := flatten(families)
+	now := time.Now()
+
+	for _, rule := range m.rules {
+		m.evaluateRule(rule, samples, now)
+	}
+}
+
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// flatten turns gathered MetricFamilies into a flat list of (name, labels,
+// value) samples, the shape Rule.matches/breaches operate on.
+func flatten(families []*dto.MetricFamily) []sample {
+	var samples []sample
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+
+			var value float64
+			switch {
+			case m.Gauge != nil:
+				value = m.GetGauge().GetValue()
+			case m.Counter != nil:
+				value = m.GetCounter().GetValue()
+			case m.Untyped != nil:
+				value = m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+
+			samples = append(samples, sample{name: family.GetName(), labels: labels, value: value})
+		}
+	}
+	return samples
+}
+
+func (m *Manager) evaluateRule(rule Rule, samples []sample, now time.Time) {
+	breaching := false
+	for _, s := range samples {
+		if s.name != rule.metric || !rule.matches(s.labels) {
+			continue
+		}
+		if rule.breaches(s.value) {
+			breaching = true
+			break
+		}
+	}
+
+	m.mu.Lock()
+	st := m.state[rule.Alert]
+	if st == nil {
+		st = &ruleState{}
+		m.state[rule.Alert] = st
+	}
+
+	if !breaching {
+		st.breachingSince = time.Time{}
+		wasFiring := st.firing
+		st.firing = false
+		m.mu.Unlock()
+
+		if wasFiring {
+			m.send(rule, st.startsAt, now)
+		}
+		return
+	}
+
+	if st.breachingSince.IsZero() {
+		st.breachingSince = now
+	}
+	matured := now.Sub(st.breachingSince) >= rule.For
+	if matured && !st.firing {
+		st.firing = true
+		st.startsAt = now
+	}
+	startsAt := st.startsAt
+	firing := st.firing
+	m.mu.Unlock()
+
+	// Keep re-sending the firing alert on every tick, not just on the rising
+	// edge: Alertmanager auto-resolves any alert it hasn't seen an update
+	// for within its resolve_timeout, so a breach that outlasts that window
+	// would otherwise flip to "resolved" in Alertmanager while still active.
+	if firing {
+		m.send(rule, startsAt, time.Time{})
+	}
+}
+
+// send posts a single alert to Alertmanager. A zero endsAt means the alert
+// is still firing; a non-zero endsAt resolves it.
+func (m *Manager) send(rule Rule, startsAt, endsAt time.Time) {
+	labels := map[string]string{"alertname": rule.Alert}
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+
+	alert := alertmanagerAlert{
+		Labels:      labels,
+		Annotations: rule.Annotations,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+	}
+
+	body, err := json.Marshal([]alertmanagerAlert{alert})
+	if err != nil {
+		level.Error(m.logger).Log("msg", "failed to marshal alert", "alert", rule.Alert, "err", err)
+		return
+	}
+
+	url := m.alertmanagerURL + "/api/v2/alerts"
+	resp, err := m.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		level.Error(m.logger).Log("msg", "failed to push alert", "alert", rule.Alert, "err", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		level.Error(m.logger).Log("msg", "alertmanager rejected alert", "alert", rule.Alert, "status", resp.StatusCode)
+	}
+}
@@ -0,0 +1,169 @@
+package alarm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func compileRule(t *testing.T, expr string) Rule {
+	t.Helper()
+	r := Rule{Alert: "test", Expr: expr}
+	if err := r.compile(); err != nil {
+		t.Fatalf("compile(%q) failed: %v", expr, err)
+	}
+	return r
+}
+
+func TestRuleCompile(t *testing.T) {
+	tests := []struct {
+		expr         string
+		wantMetric   string
+		wantOp       string
+		wantValue    float64
+		wantSelector map[string]string
+	}{
+		{expr: "node_rsyslog_up == 0", wantMetric: "node_rsyslog_up", wantOp: "==", wantValue: 0},
+		{expr: "node_filebeat_harvester_open_files > 5000", wantMetric: "node_filebeat_harvester_open_files", wantOp: ">", wantValue: 5000},
+		{
+			expr:         `node_process_up{name="filebeat"} != 1`,
+			wantMetric:   "node_process_up",
+			wantOp:       "!=",
+			wantValue:    1,
+			wantSelector: map[string]string{"name": "filebeat"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			r := compileRule(t, tt.expr)
+			if r.metric != tt.wantMetric {
+				t.Errorf("metric = %q, want %q", r.metric, tt.wantMetric)
+			}
+			if r.op != tt.wantOp {
+				t.Errorf("op = %q, want %q", r.op, tt.wantOp)
+			}
+			if r.value != tt.wantValue {
+				t.Errorf("value = %v, want %v", r.value, tt.wantValue)
+			}
+			for k, v := range tt.wantSelector {
+				if r.selector[k] != v {
+					t.Errorf("selector[%q] = %q, want %q", k, r.selector[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestRuleCompileInvalid(t *testing.T) {
+	r := Rule{Alert: "test", Expr: "not a valid expr"}
+	if err := r.compile(); err == nil {
+		t.Fatal("compile() succeeded on an invalid expr, want error")
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	r := compileRule(t, `node_process_up{name="filebeat",team="infra"} == 0`)
+
+	if !r.matches(map[string]string{"name": "filebeat", "team": "infra", "extra": "ignored"}) {
+		t.Error("matches() = false, want true for a superset of the selector")
+	}
+	if r.matches(map[string]string{"name": "filebeat"}) {
+		t.Error("matches() = true, want false when a selector label is missing")
+	}
+	if r.matches(map[string]string{"name": "rsyslog", "team": "infra"}) {
+		t.Error("matches() = true, want false when a selector label mismatches")
+	}
+}
+
+func TestRuleBreaches(t *testing.T) {
+	tests := []struct {
+		op    string
+		value float64
+		input float64
+		want  bool
+	}{
+		{"==", 0, 0, true},
+		{"==", 0, 1, false},
+		{"!=", 0, 1, true},
+		{">", 5000, 5001, true},
+		{">", 5000, 5000, false},
+		{"<", 5000, 4999, true},
+		{">=", 5000, 5000, true},
+		{"<=", 5000, 5000, true},
+	}
+
+	for _, tt := range tests {
+		r := Rule{op: tt.op, value: tt.value}
+		if got := r.breaches(tt.input); got != tt.want {
+			t.Errorf("breaches(%v) with %s %v = %v, want %v", tt.input, tt.op, tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestManagerEvaluateRuleForDuration exercises the debounce state machine:
+// a breach must persist for at least rule.For before an alert fires, and a
+// resolved notification must follow once the breach clears.
+func TestManagerEvaluateRuleForDuration(t *testing.T) {
+	var posted []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var alerts []map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&alerts); err != nil {
+			t.Fatalf("failed to decode posted alerts: %v", err)
+		}
+		posted = append(posted, alerts...)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rule := compileRule(t, "node_rsyslog_up == 0")
+	rule.For = 30 * time.Second
+
+	m := &Manager{
+		logger:          log.NewNopLogger(),
+		alertmanagerURL: server.URL,
+		client:          server.Client(),
+		state:           map[string]*ruleState{rule.Alert: {}},
+	}
+
+	down := []sample{{name: "node_rsyslog_up", labels: nil, value: 0}}
+	up := []sample{{name: "node_rsyslog_up", labels: nil, value: 1}}
+
+	t0 := time.Unix(1000, 0)
+
+	// First breach: not old enough yet, must not fire.
+	m.evaluateRule(rule, down, t0)
+	if len(posted) != 0 {
+		t.Fatalf("fired before rule.For elapsed: %d alerts posted", len(posted))
+	}
+
+	// Still breaching after rule.For: must fire exactly once.
+	m.evaluateRule(rule, down, t0.Add(rule.For))
+	if len(posted) != 1 {
+		t.Fatalf("after for-duration elapsed: %d alerts posted, want 1", len(posted))
+	}
+
+	// Still breaching: must re-send the firing alert so Alertmanager's
+	// resolve_timeout doesn't auto-resolve a breach that is still ongoing,
+	// and the re-sent alert must keep the original startsAt.
+	m.evaluateRule(rule, down, t0.Add(2*rule.For))
+	if len(posted) != 2 {
+		t.Fatalf("did not re-send while still breaching: %d alerts posted, want 2", len(posted))
+	}
+	if posted[1]["startsAt"] != posted[0]["startsAt"] {
+		t.Fatalf("re-sent alert's startsAt (%v) should match the original (%v)", posted[1]["startsAt"], posted[0]["startsAt"])
+	}
+
+	// Breach clears: must send exactly one resolved notification.
+	m.evaluateRule(rule, up, t0.Add(3*rule.For))
+	if len(posted) != 3 {
+		t.Fatalf("after breach cleared: %d alerts posted, want 3", len(posted))
+	}
+	if posted[2]["endsAt"] == posted[1]["endsAt"] {
+		t.Fatalf("resolved notification's endsAt (%v) should differ from the firing notification's (%v)", posted[2]["endsAt"], posted[1]["endsAt"])
+	}
+}
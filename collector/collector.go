@@ -0,0 +1,237 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace defines the common namespace to be used by all metrics.
+const namespace = "node"
+
+const (
+	defaultEnabled  = true
+	defaultDisabled = false
+)
+
+var (
+	factories              = make(map[string]func(logger log.Logger) (Collector, error))
+	initiatedCollectorsMtx = sync.Mutex{}
+	initiatedCollectors    = make(map[string]Collector)
+	collectorState         = make(map[string]*bool)
+	forcedCollectors       = map[string]bool{}
+)
+
+var (
+	scrapeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
+		"node_exporter: Duration of a collector scrape.",
+		[]string{"collector"}, nil,
+	)
+	scrapeSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
+		"node_exporter: Whether a collector succeeded.",
+		[]string{"collector"}, nil,
+	)
+)
+
+// Collector is the interface every collector must implement.
+type Collector interface {
+	// Update gets new metrics and exposes them via the prometheus registry.
+	Update(ch chan<- prometheus.Metric) error
+}
+
+// StartableCollector is implemented by collectors that own a background
+// resource (a log tailer, a long-poll HTTP client, an eBPF probe loop) and
+// need an explicit lifecycle instead of doing all their work inside Update.
+//
+// Start is called once, before the first scrape, with a context that is
+// cancelled on node_exporter shutdown; it must return once its background
+// goroutine(s) are running, not block for the lifetime of the process.
+// Stop is called on shutdown and must cause those goroutines to exit and
+// drain any in-flight scrape within ctx's deadline; Update must never block
+// on a background resource owned by Start, so a scrape in progress when
+// Stop is called should still complete against a collector's last-known
+// values.
+type StartableCollector interface {
+	Collector
+
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func registerCollector(collector string, isDefaultEnabled bool, factory func(logger log.Logger) (Collector, error)) {
+	var helpDefaultState string
+	if isDefaultEnabled {
+		helpDefaultState = "enabled"
+	} else {
+		helpDefaultState = "disabled"
+	}
+
+	flagName := fmt.Sprintf("collector.%s", collector)
+	flagHelp := fmt.Sprintf("Enable the %s collector (default: %s).", collector, helpDefaultState)
+	defaultValue := fmt.Sprintf("%v", isDefaultEnabled)
+
+	flag := kingpin.Flag(flagName, flagHelp).Default(defaultValue).Action(collectorFlagAction(collector)).Bool()
+	collectorState[collector] = flag
+
+	factories[collector] = factory
+}
+
+// collectorFlagAction generates a new action function for the given collector
+// to track whether it has been explicitly enabled or disabled.
+func collectorFlagAction(collector string) func(ctx *kingpin.ParseContext) error {
+	return func(ctx *kingpin.ParseContext) error {
+		forcedCollectors[collector] = true
+		return nil
+	}
+}
+
+// NodeCollector implements the prometheus.Collector interface.
+type NodeCollector struct {
+	Collectors map[string]Collector
+	logger     log.Logger
+}
+
+// NewNodeCollector creates a new NodeCollector.
+func NewNodeCollector(logger log.Logger, filters ...string) (*NodeCollector, error) {
+	f := make(map[string]bool)
+	for _, filter := range filters {
+		enabled, exist := collectorState[filter]
+		if !exist {
+			return nil, fmt.Errorf("missing collector: %s", filter)
+		}
+		if !*enabled {
+			return nil, fmt.Errorf("disabled collector: %s", filter)
+		}
+		f[filter] = true
+	}
+
+	collectors := make(map[string]Collector)
+	initiatedCollectorsMtx.Lock()
+	defer initiatedCollectorsMtx.Unlock()
+	for key, enabled := range collectorState {
+		if !*enabled || (len(f) > 0 && !f[key]) {
+			continue
+		}
+		if collector, ok := initiatedCollectors[key]; ok {
+			collectors[key] = collector
+			continue
+		}
+		collector, err := factories[key](log.With(logger, "collector", key))
+		if err != nil {
+			return nil, err
+		}
+		collectors[key] = collector
+		initiatedCollectors[key] = collector
+	}
+
+	return &NodeCollector{Collectors: collectors, logger: logger}, nil
+}
+
+// Start calls Start(ctx) on every registered collector that implements
+// StartableCollector, in parallel, and waits for them all to return.
+// Callers should call Start once, before the HTTP server begins serving
+// scrapes, and keep ctx alive until Stop is called.
+func (n NodeCollector) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(n.Collectors))
+
+	for name, c := range n.Collectors {
+		startable, ok := c.(StartableCollector)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, c StartableCollector) {
+			defer wg.Done()
+			if err := c.Start(ctx); err != nil {
+				errs <- fmt.Errorf("collector %s failed to start: %w", name, err)
+			}
+		}(name, startable)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop calls Stop(ctx) on every registered collector that implements
+// StartableCollector, in parallel, and waits for them all to return or for
+// ctx to expire, whichever comes first.
+func (n NodeCollector) Stop(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for name, c := range n.Collectors {
+		startable, ok := c.(StartableCollector)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, c StartableCollector) {
+			defer wg.Done()
+			if err := c.Stop(ctx); err != nil {
+				level.Error(n.logger).Log("msg", "collector failed to stop cleanly", "name", name, "err", err)
+			}
+		}(name, startable)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		level.Warn(n.logger).Log("msg", "timed out waiting for collectors to stop", "err", ctx.Err())
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (n NodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scrapeDurationDesc
+	ch <- scrapeSuccessDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (n NodeCollector) Collect(ch chan<- prometheus.Metric) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(n.Collectors))
+	for name, c := range n.Collectors {
+		go func(name string, c Collector) {
+			execute(name, c, ch, n.logger)
+			wg.Done()
+		}(name, c)
+	}
+	wg.Wait()
+}
+
+func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.Logger) {
+	begin := time.Now()
+	err := c.Update(ch)
+	duration := time.Since(begin)
+
+	var success float64
+	if err != nil {
+		level.Error(logger).Log("msg", "collector failed", "name", name, "duration_seconds", duration.Seconds(), "err", err)
+		success = 0
+	} else {
+		level.Debug(logger).Log("msg", "collector succeeded", "name", name, "duration_seconds", duration.Seconds())
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
+}
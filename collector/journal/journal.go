@@ -0,0 +1,131 @@
+// Package journal provides a small, reusable client for reading log entries
+// directly from systemd-journald, so that collectors can derive metrics from
+// a daemon's logs without shelling out to journalctl.
+package journal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+)
+
+// waitTimeout bounds each poll of the journal for new entries so Tail can
+// still observe context cancellation promptly.
+const waitTimeout = 5 * time.Second
+
+// Matcher narrows which journal entries Tail delivers to its handler, e.g.
+// Matcher{Field: sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT, Value: "filebeat.service"}.
+type Matcher = sdjournal.Match
+
+// Handler is invoked once per journal entry that passes Matcher. A returned
+// error is treated as "entry not consumed": Tail skips the entry without
+// persisting the cursor for it. The cursor still moves past it once a later
+// entry is handled successfully, so a restart only delivers it again if no
+// later entry was handled before that restart.
+type Handler func(entry *sdjournal.JournalEntry) error
+
+// Tail follows the journal entries matching matcher and invokes handler for
+// each one, until ctx is cancelled. Only matcher filters entries; unit names
+// the cursor file and does not restrict the entries read. If cursorDir is non-empty, the
+// journal cursor is persisted there after every successfully handled entry,
+// so a restart resumes from where it left off instead of re-delivering the
+// whole backlog; otherwise Tail starts from the current end of the journal.
+func Tail(ctx context.Context, cursorDir, unit string, matcher Matcher, handler Handler) error {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer j.Close()
+
+	if err := j.AddMatch(matcher.Field + "=" + matcher.Value); err != nil {
+		return fmt.Errorf("failed to add journal match: %w", err)
+	}
+
+	cursorPath := cursorFilePath(cursorDir, unit)
+	if !seekToCursor(j, cursorPath) {
+		if err := j.SeekTail(); err != nil {
+			return fmt.Errorf("failed to seek to journal tail: %w", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		n, err := j.Next()
+		if err != nil {
+			return fmt.Errorf("failed to advance journal: %w", err)
+		}
+		if n == 0 {
+			// Wait returns a change-type status code, not an error; any
+			// status (new entries, timeout, append, invalidate) just means
+			// it's time to call Next again.
+			j.Wait(waitTimeout)
+			continue
+		}
+
+		entry, err := j.GetEntry()
+		if err != nil {
+			return fmt.Errorf("failed to read journal entry: %w", err)
+		}
+
+		if err := handler(entry); err != nil {
+			continue
+		}
+
+		persistCursor(j, cursorPath)
+	}
+}
+
+func cursorFilePath(cursorDir, unit string) string {
+	if cursorDir == "" {
+		return ""
+	}
+	return filepath.Join(cursorDir, unit+".cursor")
+}
+
+// seekToCursor seeks j to a previously persisted cursor and skips past the
+// entry it points at (already handled in a prior run). It returns false if
+// there is no usable persisted cursor, so the caller can fall back to
+// SeekTail.
+func seekToCursor(j *sdjournal.Journal, cursorPath string) bool {
+	if cursorPath == "" {
+		return false
+	}
+
+	cursor, err := os.ReadFile(cursorPath)
+	if err != nil || len(cursor) == 0 {
+		return false
+	}
+
+	if err := j.SeekCursor(string(cursor)); err != nil {
+		return false
+	}
+	if _, err := j.NextSkip(1); err != nil {
+		return false
+	}
+
+	return true
+}
+
+func persistCursor(j *sdjournal.Journal, cursorPath string) {
+	if cursorPath == "" {
+		return
+	}
+
+	cursor, err := j.GetCursor()
+	if err != nil {
+		return
+	}
+
+	// Best-effort: a missed cursor write just means the next restart
+	// re-reads a few already-handled entries, which handlers must tolerate.
+	_ = os.WriteFile(cursorPath, []byte(cursor), 0o644)
+}
@@ -0,0 +1,295 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"gopkg.in/yaml.v2"
+)
+
+var processConfigPath = kingpin.Flag("collector.process.config", "Path to a YAML file listing the processes to watch.").Default("/etc/node_exporter/processes.yml").String()
+
+// processWatchEntry describes one process to watch, as loaded from
+// --collector.process.config. Exactly one of CmdlineRegex or PIDFile should
+// normally be set; if both are empty the process Name itself is used as a
+// plain command-line substring match.
+type processWatchEntry struct {
+	Name         string            `yaml:"name"`
+	CmdlineRegex string            `yaml:"cmdline_regex"`
+	PIDFile      string            `yaml:"pid_file"`
+	Match        map[string]string `yaml:"match"`
+
+	cmdlineRegex *regexp.Regexp
+}
+
+type processWatchConfig struct {
+	Processes []processWatchEntry `yaml:"processes"`
+}
+
+// processWatchCollector is a generalization of the old rsyslog and filebeat
+// process collectors: instead of hard-coding a process name, it watches an
+// arbitrary set of processes described by --collector.process.config and
+// reports procfs-backed metrics for each of them, mirroring the metrics
+// client_golang's ProcessCollectorOpts produces for the exporter's own
+// process.
+//
+// Unlike the rsyslog/filebeat collectors it replaces, this collector is
+// registered defaultDisabled and does nothing until a config listing at
+// least one process is supplied via --collector.process.config: there is no
+// more zero-config, always-on node_rsyslog_up. Deployments that relied on
+// that metric need a processes.yml entry such as:
+//
+//	processes:
+//	  - name: rsyslog
+//	    match: {}
+//
+// enabled with --collector.process_watch to keep an equivalent node_process_up
+// series.
+type processWatchCollector struct {
+	logger      log.Logger
+	processes   []processWatchEntry
+	matchLabels []string
+	fs          procfs.FS
+
+	up             *prometheus.Desc
+	cpuSeconds     *prometheus.Desc
+	residentMemory *prometheus.Desc
+	virtualMemory  *prometheus.Desc
+	openFDs        *prometheus.Desc
+	maxFDs         *prometheus.Desc
+	startTime      *prometheus.Desc
+	numThreads     *prometheus.Desc
+}
+
+func init() {
+	registerCollector("process_watch", defaultDisabled, NewProcessWatchCollector)
+}
+
+// NewProcessWatchCollector loads the process list from --collector.process.config
+// and returns a Collector that reports procfs metrics for each matched process.
+func NewProcessWatchCollector(logger log.Logger) (Collector, error) {
+	config, err := loadProcessWatchConfig(*processConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load process watch config %q: %w", *processConfigPath, err)
+	}
+
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+
+	labelNames := append([]string{"name"}, matchLabelNames(config.Processes)...)
+
+	return &processWatchCollector{
+		logger:      logger,
+		processes:   config.Processes,
+		matchLabels: labelNames[1:],
+		fs:          fs,
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "up"),
+			"Value is 1 if a process matching this entry's config was found, 0 otherwise.",
+			labelNames, nil,
+		),
+		cpuSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "cpu_seconds_total"),
+			"Total user and system CPU time spent by the matched process in seconds.",
+			labelNames, nil,
+		),
+		residentMemory: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "resident_memory_bytes"),
+			"Resident memory size of the matched process in bytes.",
+			labelNames, nil,
+		),
+		virtualMemory: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "virtual_memory_bytes"),
+			"Virtual memory size of the matched process in bytes.",
+			labelNames, nil,
+		),
+		openFDs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "open_fds"),
+			"Number of open file descriptors of the matched process.",
+			labelNames, nil,
+		),
+		maxFDs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "max_fds"),
+			"Maximum number of open file descriptors allowed for the matched process.",
+			labelNames, nil,
+		),
+		startTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "start_time_seconds"),
+			"Start time of the matched process since unix epoch in seconds.",
+			labelNames, nil,
+		),
+		numThreads: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "process", "num_threads"),
+			"Number of OS threads in the matched process.",
+			labelNames, nil,
+		),
+	}, nil
+}
+
+func loadProcessWatchConfig(path string) (*processWatchConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config processWatchConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	for i, p := range config.Processes {
+		if p.CmdlineRegex != "" {
+			re, err := regexp.Compile(p.CmdlineRegex)
+			if err != nil {
+				return nil, fmt.Errorf("process %q: invalid cmdline_regex: %w", p.Name, err)
+			}
+			config.Processes[i].cmdlineRegex = re
+		}
+	}
+
+	return &config, nil
+}
+
+// matchLabelNames returns the sorted union of all "match" label keys across
+// every configured process, so that every process exposes the same label set
+// regardless of which labels it declares.
+func matchLabelNames(processes []processWatchEntry) []string {
+	set := map[string]struct{}{}
+	for _, p := range processes {
+		for k := range p.Match {
+			set[k] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for k := range set {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *processWatchCollector) labelValues(p processWatchEntry) []string {
+	values := make([]string, 0, len(c.matchLabels)+1)
+	values = append(values, p.Name)
+	for _, name := range c.matchLabels {
+		values = append(values, p.Match[name])
+	}
+	return values
+}
+
+func (c *processWatchCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, p := range c.processes {
+		labels := c.labelValues(p)
+
+		pid, err := c.findPID(p)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "process not found", "name", p.Name, "err", err)
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0, labels...)
+			continue
+		}
+
+		proc, err := c.fs.Proc(pid)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.up, fmt.Errorf("process %q (pid %d): %w", p.Name, pid, err))
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1, labels...)
+		c.collectProcMetrics(ch, proc, labels)
+	}
+
+	return nil
+}
+
+func (c *processWatchCollector) collectProcMetrics(ch chan<- prometheus.Metric, proc procfs.Proc, labels []string) {
+	stat, err := proc.Stat()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.cpuSeconds, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.cpuSeconds, prometheus.CounterValue, stat.CPUTime(), labels...)
+	ch <- prometheus.MustNewConstMetric(c.residentMemory, prometheus.GaugeValue, float64(stat.ResidentMemory()), labels...)
+	ch <- prometheus.MustNewConstMetric(c.virtualMemory, prometheus.GaugeValue, float64(stat.VirtualMemory()), labels...)
+	ch <- prometheus.MustNewConstMetric(c.numThreads, prometheus.GaugeValue, float64(stat.NumThreads), labels...)
+
+	if startTime, err := stat.StartTime(); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.startTime, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.startTime, prometheus.GaugeValue, startTime, labels...)
+	}
+
+	if fds, err := proc.FileDescriptorsLen(); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.openFDs, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.openFDs, prometheus.GaugeValue, float64(fds), labels...)
+	}
+
+	if limits, err := proc.Limits(); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.maxFDs, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.maxFDs, prometheus.GaugeValue, float64(limits.OpenFiles), labels...)
+	}
+}
+
+// findPID resolves the PID for a configured process, preferring an explicit
+// pid_file, then falling back to scanning procfs for a matching cmdline_regex
+// or, lacking that, a plain substring match against the process name.
+func (c *processWatchCollector) findPID(p processWatchEntry) (int, error) {
+	if p.PIDFile != "" {
+		return readPIDFile(p.PIDFile)
+	}
+
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, proc := range procs {
+		cmdline, err := proc.CmdLine()
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+
+		if p.matchesCmdline(strings.Join(cmdline, " ")) {
+			return proc.PID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no process matched")
+}
+
+// matchesCmdline reports whether joined (a process's space-joined argv)
+// matches this entry: its cmdline_regex if one was configured, otherwise a
+// plain substring match against Name.
+func (p processWatchEntry) matchesCmdline(joined string) bool {
+	if p.cmdlineRegex != nil {
+		return p.cmdlineRegex.MatchString(joined)
+	}
+	return strings.Contains(joined, p.Name)
+}
+
+func readPIDFile(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid in %q: %w", path, err)
+	}
+
+	return pid, nil
+}
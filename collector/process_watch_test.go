@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestProcessWatchEntryMatchesCmdline(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   processWatchEntry
+		cmdline string
+		want    bool
+	}{
+		{
+			name:    "plain name substring match",
+			entry:   processWatchEntry{Name: "rsyslog"},
+			cmdline: "/usr/sbin/rsyslogd -n",
+			want:    true,
+		},
+		{
+			name:    "plain name no match",
+			entry:   processWatchEntry{Name: "rsyslog"},
+			cmdline: "/usr/sbin/filebeat -c filebeat.yml",
+			want:    false,
+		},
+		{
+			name:    "cmdline_regex match takes precedence over name",
+			entry:   processWatchEntry{Name: "java", cmdlineRegex: regexp.MustCompile(`-jar\s+myapp\.jar`)},
+			cmdline: "/usr/bin/java -jar myapp.jar",
+			want:    true,
+		},
+		{
+			name:    "cmdline_regex no match even if name would match",
+			entry:   processWatchEntry{Name: "java", cmdlineRegex: regexp.MustCompile(`-jar\s+other\.jar`)},
+			cmdline: "/usr/bin/java -jar myapp.jar",
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.matchesCmdline(tt.cmdline); got != tt.want {
+				t.Errorf("matchesCmdline(%q) = %v, want %v", tt.cmdline, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchLabelNames(t *testing.T) {
+	processes := []processWatchEntry{
+		{Name: "rsyslog", Match: map[string]string{"team": "infra"}},
+		{Name: "filebeat", Match: map[string]string{"team": "infra", "tier": "logging"}},
+		{Name: "nginx"},
+	}
+
+	got := matchLabelNames(processes)
+	want := []string{"team", "tier"}
+
+	if len(got) != len(want) {
+		t.Fatalf("matchLabelNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("matchLabelNames() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestProcessWatchCollectorLabelValues(t *testing.T) {
+	processes := []processWatchEntry{
+		{Name: "rsyslog", Match: map[string]string{"team": "infra"}},
+		{Name: "nginx", Match: map[string]string{"tier": "logging"}},
+	}
+	c := &processWatchCollector{
+		processes:   processes,
+		matchLabels: matchLabelNames(processes),
+	}
+
+	got := c.labelValues(processes[0])
+	want := []string{"rsyslog", "infra", ""}
+
+	if len(got) != len(want) {
+		t.Fatalf("labelValues() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("labelValues() = %v, want %v", got, want)
+		}
+	}
+}